@@ -2,11 +2,13 @@ package rkcursor
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/rookie-ninja/rk-entry/v2/entry"
 	"github.com/rookie-ninja/rk-entry/v2/middleware"
 	"github.com/rookie-ninja/rk-query"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 	"runtime"
 	"strings"
@@ -37,6 +39,7 @@ func init() {
 			"appName",
 			"operation",
 			"status",
+			"errorClass",
 		},
 		values: []string{
 			"",
@@ -67,9 +70,32 @@ func init() {
 }
 
 func StartMonitor() *cursor {
+	operation := funcName()
+	entryName, entryType := label.entryNameAndType()
+	ctx, span := startSpan(context.Background(), nil, operation, entryName, entryType)
+
+	return &cursor{
+		start:     time.Now(),
+		operation: operation,
+		ctx:       ctx,
+		span:      span,
+	}
+}
+
+// StartMonitorCtx behaves like StartMonitor but, when tracing is enabled via
+// SetTracerProvider, opens the cursor's span as a child of ctx. This lets
+// cursors nested inside HTTP/gRPC middleware join the trace of the incoming
+// request instead of starting a new one.
+func StartMonitorCtx(ctx context.Context) *cursor {
+	operation := funcName()
+	entryName, entryType := label.entryNameAndType()
+	ctx, span := startSpan(ctx, nil, operation, entryName, entryType)
+
 	return &cursor{
 		start:     time.Now(),
-		operation: funcName(),
+		operation: operation,
+		ctx:       ctx,
+		span:      span,
 	}
 }
 
@@ -91,16 +117,71 @@ type CustomCursor struct {
 	Now       time.Time
 	entryName string
 	entryType string
+
+	// MetricType overrides the global metricType for cursors started from
+	// this instance. Left as "" to fall back to the global setting.
+	MetricType MetricType
+	// SummaryVec, when non-nil, is observed into instead of the global
+	// summaryVec, letting an entry register its own namespace without
+	// touching global state.
+	SummaryVec *prometheus.SummaryVec
+	// HistogramVec, when non-nil, is observed into instead of the global
+	// histogramVec.
+	HistogramVec *prometheus.HistogramVec
+
+	// Tracer, when non-nil, is used instead of the global tracer configured
+	// via SetTracerProvider.
+	Tracer trace.Tracer
+
+	// ErrorSampler, when non-nil, is consulted instead of the global
+	// errorSampler.
+	ErrorSampler Sampler
+	// LatencySampler, when non-nil, is consulted instead of the global
+	// latencySampler.
+	LatencySampler Sampler
 }
 
 func (c *CustomCursor) StartMonitor() *cursor {
+	operation := funcName()
+	ctx, span := startSpan(context.Background(), c.Tracer, operation, c.entryName, c.entryType)
+
 	return &cursor{
-		entryName: c.entryName,
-		entryType: c.entryType,
-		start:     time.Now(),
-		operation: funcName(),
-		logger:    c.Logger,
-		event:     c.Event,
+		entryName:      c.entryName,
+		entryType:      c.entryType,
+		start:          time.Now(),
+		operation:      operation,
+		logger:         c.Logger,
+		event:          c.Event,
+		metricType:     c.MetricType,
+		summaryVec:     c.SummaryVec,
+		histogramVec:   c.HistogramVec,
+		ctx:            ctx,
+		span:           span,
+		errorSampler:   c.ErrorSampler,
+		latencySampler: c.LatencySampler,
+	}
+}
+
+// StartMonitorCtx behaves like StartMonitor but opens the cursor's span as a
+// child of ctx when tracing is enabled.
+func (c *CustomCursor) StartMonitorCtx(ctx context.Context) *cursor {
+	operation := funcName()
+	ctx, span := startSpan(ctx, c.Tracer, operation, c.entryName, c.entryType)
+
+	return &cursor{
+		entryName:      c.entryName,
+		entryType:      c.entryType,
+		start:          time.Now(),
+		operation:      operation,
+		logger:         c.Logger,
+		event:          c.Event,
+		metricType:     c.MetricType,
+		summaryVec:     c.SummaryVec,
+		histogramVec:   c.HistogramVec,
+		ctx:            ctx,
+		span:           span,
+		errorSampler:   c.ErrorSampler,
+		latencySampler: c.LatencySampler,
 	}
 }
 
@@ -132,7 +213,21 @@ type promLabel struct {
 	values []string
 }
 
-func (l *promLabel) getValues(op string, entryName, entryType string, err error) []string {
+// entryNameAndType returns the current global entryName/entryType under
+// label.mutex, so readers never race with OverrideEntryNameAndType.
+func (l *promLabel) entryNameAndType() (string, string) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	return l.values[0], l.values[1]
+}
+
+// getValues builds the label values for a metric observation. errClass is
+// the caller's already-classified error label (empty for a successful
+// call) rather than being recomputed here, since classify() is consulted
+// once per error in ObserveError and reused for both the log line and the
+// metric label.
+func (l *promLabel) getValues(op string, entryName, entryType string, err error, errClass string) []string {
 	label.mutex.Lock()
 	defer label.mutex.Unlock()
 
@@ -141,7 +236,7 @@ func (l *promLabel) getValues(op string, entryName, entryType string, err error)
 		status = "ERROR"
 	}
 
-	res := append(l.values, op, status)
+	res := append(l.values, op, status, errClass)
 	res[0] = entryName
 	res[1] = entryType
 
@@ -158,6 +253,28 @@ type cursor struct {
 	logger    *zap.Logger
 	entryName string
 	entryType string
+
+	metricType   MetricType
+	summaryVec   *prometheus.SummaryVec
+	histogramVec *prometheus.HistogramVec
+
+	ctx  context.Context
+	span trace.Span
+
+	errorSampler   Sampler
+	latencySampler Sampler
+
+	// errClass is computed once by ObserveError and reused by Finish, so a
+	// single errored cursor only pays classify()'s matcher/cardinality-guard
+	// cost once.
+	errClass string
+}
+
+// Context returns the context.Context carrying the cursor's span (or, with
+// tracing disabled, the context the cursor was started with), suitable for
+// passing down to further calls so they join the same trace.
+func (c *cursor) Context() context.Context {
+	return c.ctx
 }
 
 func (c *cursor) ObserveError(err error) error {
@@ -166,36 +283,94 @@ func (c *cursor) ObserveError(err error) error {
 	}
 
 	c.err = err
+	c.errClass = classify(err)
 
-	stack := stacks()
-
-	var builder bytes.Buffer
+	maybeCaptureHeap(c.operation)
 
-	// print error message
-	builder.WriteString(fmt.Sprintf("%s\n", err.Error()))
-	// print stack function
-	for i := range stack {
-		pc := stack[i] - 1
-		builder.WriteString(fmt.Sprintf("%d)\t%s\n", i, fileline(pc)))
+	sampler := c.errorSampler
+	if sampler == nil {
+		sampler = currentErrorSampler()
 	}
 
-	if c.logger != nil {
-		c.logger.WithOptions(zap.AddCallerSkip(1)).Error(builder.String())
+	if sampler != nil && !sampler.Sample(c.operation, 0, err) {
+		dropped(c.operation, "error")
 	} else {
-		logger.WithOptions(zap.AddCallerSkip(1)).Error(builder.String())
+		stack := stacks()
+
+		var builder bytes.Buffer
+
+		// print error message
+		builder.WriteString(fmt.Sprintf("%s\n", err.Error()))
+		// print stack function
+		for i := range stack {
+			pc := stack[i] - 1
+			builder.WriteString(fmt.Sprintf("%d)\t%s\n", i, fileline(pc)))
+		}
+
+		if c.logger != nil {
+			c.logger.WithOptions(zap.AddCallerSkip(1)).Error(builder.String(), zap.String("errorClass", c.errClass))
+		} else {
+			logger.WithOptions(zap.AddCallerSkip(1)).Error(builder.String(), zap.String("errorClass", c.errClass))
+		}
 	}
 
 	if c.event != nil {
 		c.event.IncCounter(strings.Join([]string{c.operation, "ERROR"}, "_"), 1)
 	}
 
+	if c.span != nil {
+		c.span.RecordError(err)
+	}
+
 	return err
 }
 
 func (c *cursor) Finish() {
+	defer endSpan(c.span, c.err)
+
 	elapsedNano := time.Now().Sub(c.start).Nanoseconds()
 
-	observer, _ := summaryVec.GetMetricWithLabelValues(label.getValues(c.operation, c.entryName, c.entryType, c.err)...)
+	maybeCaptureCPU(c.operation, time.Duration(elapsedNano))
+
+	sampler := c.latencySampler
+	if sampler == nil {
+		sampler = currentLatencySampler()
+	}
+
+	if sampler != nil && !sampler.Sample(c.operation, time.Duration(elapsedNano), c.err) {
+		dropped(c.operation, "latency")
+		return
+	}
+
+	labelValues := label.getValues(c.operation, c.entryName, c.entryType, c.err, c.errClass)
+
+	mt := c.metricType
+	if mt == "" {
+		mt = currentMetricType()
+	}
+
+	if mt == MetricTypeHistogram || mt == MetricTypeNativeHistogram {
+		hv := c.histogramVec
+		if hv == nil {
+			hv = HistogramVec()
+		}
+		if hv == nil {
+			return
+		}
+		observer, _ := hv.GetMetricWithLabelValues(labelValues...)
+		if observer == nil {
+			return
+		}
+		observer.Observe(float64(elapsedNano))
+		return
+	}
+
+	sv := c.summaryVec
+	if sv == nil {
+		sv = summaryVec
+	}
+
+	observer, _ := sv.GetMetricWithLabelValues(labelValues...)
 	if observer == nil {
 		return
 	}
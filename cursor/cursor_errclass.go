@@ -0,0 +1,145 @@
+package rkcursor
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ErrorClassifier turns an error into a short, low-cardinality label used
+// to populate the errorClass Prometheus label and the log line emitted by
+// ObserveError.
+type ErrorClassifier interface {
+	Classify(err error) string
+}
+
+// maxErrorClasses bounds how many distinct user-registered errorClass
+// values (via RegisterErrorClass or SetErrorClassifier) are allowed before
+// new ones fall back to "other", protecting Prometheus from label
+// cardinality blowup when a registered matcher is too specific. Built-in
+// classes (see reservedErrorClasses) are exempt from this budget, since
+// they're a fixed, known-small set regardless of how many gRPC codes or
+// other built-in errors a service happens to see.
+const maxErrorClasses = 64
+
+// reservedErrorClasses are the labels the built-in classifier can produce
+// on its own: every gRPC status code, plus the context/io.EOF shortcuts and
+// the generic fallbacks. They never count against maxErrorClasses.
+var reservedErrorClasses = func() map[string]bool {
+	reserved := map[string]bool{
+		"deadline_exceeded": true,
+		"canceled":          true,
+		"eof":               true,
+		"error":             true,
+		"other":             true,
+	}
+	for c := codes.OK; c <= codes.Unauthenticated; c++ {
+		reserved[strings.ToLower(c.String())] = true
+	}
+	return reserved
+}()
+
+type errorMatcher struct {
+	matches func(err error) bool
+	label   string
+}
+
+type defaultErrorClassifier struct {
+	mutex    sync.Mutex
+	matchers []errorMatcher
+}
+
+var builtinClassifier = &defaultErrorClassifier{}
+
+// RegisterErrorClass adds a matcher consulted, in registration order,
+// before the built-in rules (context.DeadlineExceeded, context.Canceled,
+// gRPC status code, io.EOF). The first matching matcher wins.
+func RegisterErrorClass(matcher func(err error) bool, label string) {
+	builtinClassifier.mutex.Lock()
+	defer builtinClassifier.mutex.Unlock()
+
+	builtinClassifier.matchers = append(builtinClassifier.matchers, errorMatcher{matches: matcher, label: label})
+}
+
+func (c *defaultErrorClassifier) Classify(err error) string {
+	c.mutex.Lock()
+	matchers := c.matchers
+	c.mutex.Unlock()
+
+	for _, m := range matchers {
+		if m.matches(err) {
+			return m.label
+		}
+	}
+
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return "deadline_exceeded"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case errors.Is(err, io.EOF):
+		return "eof"
+	}
+
+	if s, ok := status.FromError(err); ok {
+		return strings.ToLower(s.Code().String())
+	}
+
+	return "error"
+}
+
+var (
+	classifierMutex  sync.Mutex
+	customClassifier ErrorClassifier
+	classSeenMutex   sync.Mutex
+	classSeen        = map[string]bool{}
+)
+
+// SetErrorClassifier overrides the global ErrorClassifier entirely. Pass
+// nil (the default) to use the built-in rules plus any RegisterErrorClass
+// matchers.
+func SetErrorClassifier(c ErrorClassifier) {
+	classifierMutex.Lock()
+	defer classifierMutex.Unlock()
+
+	customClassifier = c
+}
+
+// classify returns the errorClass label value for err. Reserved (built-in)
+// classes are always returned as-is; anything else enforces maxErrorClasses
+// by collapsing any class beyond the limit into "other".
+func classify(err error) string {
+	classifierMutex.Lock()
+	c := customClassifier
+	classifierMutex.Unlock()
+
+	var class string
+	if c != nil {
+		class = c.Classify(err)
+	} else {
+		class = builtinClassifier.Classify(err)
+	}
+
+	if class == "" {
+		class = "other"
+	}
+
+	if reservedErrorClasses[class] {
+		return class
+	}
+
+	classSeenMutex.Lock()
+	defer classSeenMutex.Unlock()
+
+	if !classSeen[class] && len(classSeen) >= maxErrorClasses {
+		return "other"
+	}
+	classSeen[class] = true
+
+	return class
+}
@@ -0,0 +1,57 @@
+package rkcursor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+)
+
+func TestBuiltinClassifierRecognizesKnownErrors(t *testing.T) {
+	cases := []struct {
+		name     string
+		err      error
+		expected string
+	}{
+		{"deadline exceeded", context.DeadlineExceeded, "deadline_exceeded"},
+		{"canceled", context.Canceled, "canceled"},
+		{"eof", io.EOF, "eof"},
+		{"wrapped eof", fmt.Errorf("read failed: %w", io.EOF), "eof"},
+		{"unrecognized", errors.New("boom"), "error"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := builtinClassifier.Classify(tc.err); got != tc.expected {
+				t.Errorf("Classify(%v) = %q, want %q", tc.err, got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestClassifyFallsBackToOtherPastCardinalityLimit(t *testing.T) {
+	classSeenMutex.Lock()
+	classSeen = map[string]bool{}
+	classSeenMutex.Unlock()
+
+	builtinClassifier.mutex.Lock()
+	builtinClassifier.matchers = nil
+	builtinClassifier.mutex.Unlock()
+
+	for i := 0; i < maxErrorClasses; i++ {
+		class := fmt.Sprintf("custom-%d", i)
+		RegisterErrorClass(func(err error) bool { return err.Error() == class }, class)
+
+		if got := classify(errors.New(class)); got != class {
+			t.Fatalf("expected class %q to be kept within the cardinality limit, got %q", class, got)
+		}
+	}
+
+	const overflow = "custom-overflow"
+	RegisterErrorClass(func(err error) bool { return err.Error() == overflow }, overflow)
+
+	if got := classify(errors.New(overflow)); got != "other" {
+		t.Fatalf("expected a new class beyond maxErrorClasses to fall back to \"other\", got %q", got)
+	}
+}
@@ -0,0 +1,147 @@
+package rkcursor
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const metricsKeyHistogram = "elapsedNanoHistogram"
+
+// MetricType defines which prometheus collector a cursor observes its
+// elapsed time into.
+type MetricType string
+
+const (
+	// MetricTypeSummary observes into the classic SummaryVec with fixed
+	// quantile objectives. This is the default and preserves existing
+	// behavior.
+	MetricTypeSummary MetricType = "summary"
+
+	// MetricTypeHistogram observes into a HistogramVec with the buckets
+	// configured via SetBuckets(), enabling server-side aggregation across
+	// instances (e.g. Grafana heatmap panels).
+	MetricTypeHistogram MetricType = "histogram"
+
+	// MetricTypeNativeHistogram observes into a HistogramVec configured
+	// with a NativeHistogramBucketFactor, producing sparse high-resolution
+	// buckets without pre-declared boundaries (prometheus/client_golang
+	// v1.14+).
+	MetricTypeNativeHistogram MetricType = "nativeHistogram"
+)
+
+// defaultBuckets is scaled in nanoseconds, not seconds, because Finish()
+// observes elapsedNano (matching the existing SummaryVec and the
+// elapsedNano/elapsedNanoHistogram metric names). It spans 100us to just
+// over 200ms.
+var defaultBuckets = prometheus.ExponentialBuckets(1e5, 2, 12)
+
+var (
+	metricMutex  sync.Mutex
+	metricType   = MetricTypeSummary
+	histogramVec *prometheus.HistogramVec
+	buckets      = defaultBuckets
+)
+
+// histogramVecType is the MetricType histogramVec was built for.
+var histogramVecType MetricType
+
+// SetMetricType switches the collector global cursors observe elapsed time
+// into. The HistogramVec is created and registered with the default
+// registerer lazily on first use, so callers who never opt in never pay for
+// it.
+func SetMetricType(t MetricType) {
+	metricMutex.Lock()
+	defer metricMutex.Unlock()
+
+	metricType = t
+
+	if t == MetricTypeHistogram || t == MetricTypeNativeHistogram {
+		// A vec built for the other histogram kind shares this Desc but not
+		// its bucket layout, so it is unregistered and rebuilt.
+		if histogramVec != nil && histogramVecType != t {
+			prometheus.DefaultRegisterer.Unregister(histogramVec)
+			histogramVec = nil
+		}
+		initHistogramVec()
+	}
+}
+
+// SetBuckets overrides the bucket boundaries used by MetricTypeHistogram.
+// It has no effect on MetricTypeNativeHistogram, which derives its buckets
+// automatically via NativeHistogramBucketFactor, so an already-registered
+// native histogramVec is left untouched. Buckets aren't part of a metric's
+// Desc, so changing them after a histogramVec is already registered
+// requires unregistering the old collector first, or the registry would
+// just hand back the old buckets.
+func SetBuckets(b []float64) {
+	metricMutex.Lock()
+	defer metricMutex.Unlock()
+
+	buckets = b
+
+	if metricType != MetricTypeHistogram {
+		return
+	}
+
+	if histogramVec != nil {
+		prometheus.DefaultRegisterer.Unregister(histogramVec)
+		histogramVec = nil
+	}
+
+	initHistogramVec()
+}
+
+// initHistogramVec builds and registers histogramVec for the currently
+// selected metricType. Caller must hold metricMutex.
+func initHistogramVec() {
+	if histogramVec != nil {
+		return
+	}
+
+	opts := prometheus.HistogramOpts{
+		Namespace: "rk",
+		Subsystem: "cursor",
+		Name:      metricsKeyHistogram,
+		Help:      fmt.Sprintf("Histogram of cursor with labels:%s", label.keys),
+	}
+
+	if metricType == MetricTypeNativeHistogram {
+		opts.NativeHistogramBucketFactor = 1.1
+	} else {
+		opts.Buckets = buckets
+	}
+
+	newVec := prometheus.NewHistogramVec(opts, label.keys)
+	if err := prometheus.DefaultRegisterer.Register(newVec); err != nil {
+		if are, ok := err.(prometheus.AlreadyRegisteredError); ok {
+			histogramVec = are.ExistingCollector.(*prometheus.HistogramVec)
+			return
+		}
+
+		logger.Sugar().Errorf("failed to register cursor histogram: %v", err)
+		return
+	}
+
+	histogramVec = newVec
+	histogramVecType = metricType
+}
+
+// HistogramVec returns the global HistogramVec, or nil if SetMetricType()
+// has never selected MetricTypeHistogram or MetricTypeNativeHistogram.
+func HistogramVec() *prometheus.HistogramVec {
+	metricMutex.Lock()
+	defer metricMutex.Unlock()
+
+	return histogramVec
+}
+
+// currentMetricType returns the global metricType under metricMutex, so
+// readers on the Finish() hot path never race with SetMetricType.
+func currentMetricType() MetricType {
+	metricMutex.Lock()
+	defer metricMutex.Unlock()
+
+	return metricType
+}
@@ -0,0 +1,19 @@
+package rkcursor
+
+import "testing"
+
+func TestDefaultBucketsAreNanosecondScaled(t *testing.T) {
+	if len(defaultBuckets) == 0 {
+		t.Fatal("defaultBuckets must not be empty")
+	}
+
+	// Finish() observes elapsedNano (nanoseconds), so a typical 5ms call
+	// (5e6 ns) must land in a finite bucket rather than the +Inf overflow
+	// bucket a second-scale table (prometheus.DefBuckets) would produce.
+	const fiveMillisInNanos = 5e6
+
+	top := defaultBuckets[len(defaultBuckets)-1]
+	if top <= fiveMillisInNanos {
+		t.Fatalf("top bucket %v is not above a typical nanosecond observation %v; buckets are not nanosecond-scaled", top, fiveMillisInNanos)
+	}
+}
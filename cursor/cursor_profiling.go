@@ -0,0 +1,218 @@
+package rkcursor
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/pprof"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"compress/gzip"
+)
+
+// ProfilingOpts configures EnableProfiling.
+type ProfilingOpts struct {
+	// CPUThreshold is the latency above which cursor.Finish() arms a short
+	// CPU profile capture for that operation. Zero disables CPU profiling.
+	CPUThreshold time.Duration
+	// CPUProfileDuration is how long an armed CPU profile runs. Defaults to
+	// 10 seconds.
+	CPUProfileDuration time.Duration
+	// HeapThreshold is the minimum current heap allocation, in bytes,
+	// required before ObserveError dumps a heap profile.
+	HeapThreshold uint64
+	// HeapWhitelist restricts heap dumps on error to these operation
+	// names. An empty whitelist disables heap dumps entirely.
+	HeapWhitelist []string
+	// HeapCooldown is the minimum time between heap dumps for a given
+	// operation, guarding against a ReadMemStats stop-the-world pause (and
+	// a flood of profiles) on every error during an error storm. Defaults
+	// to 10 seconds.
+	HeapCooldown time.Duration
+	// OutputDir is where profiles are written, as
+	// <operation>-<kind>-<unixNano>.pb.gz.
+	OutputDir string
+}
+
+var profiling struct {
+	sync.Mutex
+	enabled        bool
+	opts           ProfilingOpts
+	whitelist      map[string]bool
+	capturing      map[string]bool
+	heapCooldownAt map[string]time.Time
+}
+
+// EnableProfiling arms on-demand CPU/heap profiling scoped to cursors that
+// are actually slow or erroring, instead of always-on collection.
+func EnableProfiling(opts ProfilingOpts) {
+	profiling.Lock()
+	defer profiling.Unlock()
+
+	if opts.CPUProfileDuration <= 0 {
+		opts.CPUProfileDuration = 10 * time.Second
+	}
+	if opts.HeapCooldown <= 0 {
+		opts.HeapCooldown = 10 * time.Second
+	}
+
+	whitelist := make(map[string]bool, len(opts.HeapWhitelist))
+	for _, op := range opts.HeapWhitelist {
+		whitelist[op] = true
+	}
+
+	profiling.enabled = true
+	profiling.opts = opts
+	profiling.whitelist = whitelist
+	profiling.capturing = map[string]bool{}
+	profiling.heapCooldownAt = map[string]time.Time{}
+
+	os.MkdirAll(opts.OutputDir, 0755)
+}
+
+// maybeCaptureCPU arms a background CPU profile of opts.CPUProfileDuration
+// when elapsed exceeds CPUThreshold, skipping operations already being
+// captured.
+func maybeCaptureCPU(operation string, elapsed time.Duration) {
+	profiling.Lock()
+	if !profiling.enabled || profiling.opts.CPUThreshold <= 0 || elapsed <= profiling.opts.CPUThreshold || profiling.capturing[operation] {
+		profiling.Unlock()
+		return
+	}
+	profiling.capturing[operation] = true
+	opts := profiling.opts
+	profiling.Unlock()
+
+	go func() {
+		defer func() {
+			profiling.Lock()
+			delete(profiling.capturing, operation)
+			profiling.Unlock()
+		}()
+
+		path := profilePath(opts.OutputDir, operation, "cpu")
+		f, err := os.Create(path)
+		if err != nil {
+			logger.Sugar().Errorf("failed to create cpu profile %s: %v", path, err)
+			return
+		}
+		defer f.Close()
+
+		gz := gzip.NewWriter(f)
+		defer gz.Close()
+
+		if err := pprof.StartCPUProfile(gz); err != nil {
+			logger.Sugar().Errorf("failed to start cpu profile: %v", err)
+			return
+		}
+		time.Sleep(opts.CPUProfileDuration)
+		pprof.StopCPUProfile()
+	}()
+}
+
+// maybeCaptureHeap dumps a heap profile when operation is whitelisted and
+// the current heap allocation exceeds HeapThreshold, at most once per
+// HeapCooldown per operation. The cooldown is claimed before touching
+// runtime.ReadMemStats so an error storm on a whitelisted operation can't
+// stop-the-world on every single error.
+func maybeCaptureHeap(operation string) {
+	profiling.Lock()
+	if !profiling.enabled || !profiling.whitelist[operation] {
+		profiling.Unlock()
+		return
+	}
+
+	now := time.Now()
+	if until, ok := profiling.heapCooldownAt[operation]; ok && now.Before(until) {
+		profiling.Unlock()
+		return
+	}
+
+	opts := profiling.opts
+	profiling.heapCooldownAt[operation] = now.Add(opts.HeapCooldown)
+	profiling.Unlock()
+
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	if mem.HeapAlloc < opts.HeapThreshold {
+		return
+	}
+
+	path := profilePath(opts.OutputDir, operation, "heap")
+	f, err := os.Create(path)
+	if err != nil {
+		logger.Sugar().Errorf("failed to create heap profile %s: %v", path, err)
+		return
+	}
+	defer f.Close()
+
+	gz := gzip.NewWriter(f)
+	defer gz.Close()
+
+	pprof.WriteHeapProfile(gz)
+}
+
+func profilePath(dir, operation, kind string) string {
+	return filepath.Join(dir, fmt.Sprintf("%s-%s-%d.pb.gz", operation, kind, time.Now().UnixNano()))
+}
+
+// ListProfiles returns the profile file names currently stored under
+// OutputDir, most recent first.
+func ListProfiles() ([]string, error) {
+	profiling.Lock()
+	dir := profiling.opts.OutputDir
+	profiling.Unlock()
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		if !e.IsDir() {
+			names = append(names, e.Name())
+		}
+	}
+
+	// Names end in -<unixNano>.pb.gz, so order by that timestamp rather than
+	// by the whole name, which would group profiles by operation first.
+	unixNano := func(name string) int64 {
+		name = strings.TrimSuffix(name, ".pb.gz")
+		ts, _ := strconv.ParseInt(name[strings.LastIndex(name, "-")+1:], 10, 64)
+		return ts
+	}
+	sort.SliceStable(names, func(i, j int) bool { return unixNano(names[i]) > unixNano(names[j]) })
+
+	return names, nil
+}
+
+// ServeHTTP lets an ops UI retrieve captured profiles: GET / lists file
+// names as JSON, GET /<name> streams the gzip'd profile itself.
+func ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	profiling.Lock()
+	dir := profiling.opts.OutputDir
+	profiling.Unlock()
+
+	name := strings.Trim(r.URL.Path, "/")
+	if name == "" {
+		names, err := ListProfiles()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(names)
+		return
+	}
+
+	http.ServeFile(w, r, filepath.Join(dir, filepath.Base(name)))
+}
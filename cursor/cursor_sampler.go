@@ -0,0 +1,176 @@
+package rkcursor
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Sampler decides whether a particular cursor observation should be kept.
+// operation is the cursor's operation name, elapsed is the measured
+// latency (zero when consulted from ObserveError), and err is the observed
+// error, if any. Returning false drops the observation and increments
+// rk_cursor_dropped_total instead.
+type Sampler interface {
+	Sample(operation string, elapsed time.Duration, err error) bool
+}
+
+var (
+	samplerMutex   sync.Mutex
+	errorSampler   Sampler
+	latencySampler Sampler
+)
+
+// SetErrorSampler configures the global sampler consulted by ObserveError
+// before it builds a stack trace and logs. Pass nil (the default) to
+// always sample.
+func SetErrorSampler(s Sampler) {
+	samplerMutex.Lock()
+	defer samplerMutex.Unlock()
+
+	errorSampler = s
+}
+
+// SetLatencySampler configures the global sampler consulted by Finish
+// before it observes into the latency metric. Pass nil (the default) to
+// always sample.
+func SetLatencySampler(s Sampler) {
+	samplerMutex.Lock()
+	defer samplerMutex.Unlock()
+
+	latencySampler = s
+}
+
+// currentErrorSampler and currentLatencySampler return the global samplers
+// under samplerMutex, so readers on the ObserveError/Finish hot path never
+// race with SetErrorSampler/SetLatencySampler.
+func currentErrorSampler() Sampler {
+	samplerMutex.Lock()
+	defer samplerMutex.Unlock()
+
+	return errorSampler
+}
+
+func currentLatencySampler() Sampler {
+	samplerMutex.Lock()
+	defer samplerMutex.Unlock()
+
+	return latencySampler
+}
+
+var droppedVec = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "rk",
+	Subsystem: "cursor",
+	Name:      "dropped_total",
+	Help:      "Total number of cursor observations dropped by a sampler, by operation and reason",
+}, []string{"operation", "reason"})
+
+func init() {
+	prometheus.DefaultRegisterer.Register(droppedVec)
+}
+
+func dropped(operation, reason string) {
+	droppedVec.WithLabelValues(operation, reason).Inc()
+}
+
+// ************* Samplers *************
+
+// fixedRateSampler keeps 1 observation out of every n, counted
+// independently per operation.
+type fixedRateSampler struct {
+	n       int64
+	mutex   sync.Mutex
+	counter map[string]int64
+}
+
+// NewFixedRateSampler returns a Sampler that keeps 1-in-n observations,
+// counted independently per operation.
+func NewFixedRateSampler(n int64) Sampler {
+	if n < 1 {
+		n = 1
+	}
+
+	return &fixedRateSampler{
+		n:       n,
+		counter: map[string]int64{},
+	}
+}
+
+func (s *fixedRateSampler) Sample(operation string, _ time.Duration, _ error) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.counter[operation]++
+	return s.counter[operation]%s.n == 0
+}
+
+// tokenBucketSampler rate limits samples per operation using a token
+// bucket refilled at ratePerSec, holding at most burst tokens.
+type tokenBucketSampler struct {
+	ratePerSec float64
+	burst      float64
+	mutex      sync.Mutex
+	buckets    map[string]*tokenBucket
+}
+
+type tokenBucket struct {
+	tokens     float64
+	lastRefill time.Time
+}
+
+// NewTokenBucketSampler returns a Sampler that allows up to ratePerSec
+// samples per second, with bursts up to burst, tracked independently per
+// operation key.
+func NewTokenBucketSampler(ratePerSec float64, burst int) Sampler {
+	if burst < 1 {
+		burst = 1
+	}
+
+	return &tokenBucketSampler{
+		ratePerSec: ratePerSec,
+		burst:      float64(burst),
+		buckets:    map[string]*tokenBucket{},
+	}
+}
+
+func (s *tokenBucketSampler) Sample(operation string, _ time.Duration, _ error) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := time.Now()
+	b, ok := s.buckets[operation]
+	if !ok {
+		b = &tokenBucket{tokens: s.burst, lastRefill: now}
+		s.buckets[operation] = b
+	}
+
+	b.tokens += now.Sub(b.lastRefill).Seconds() * s.ratePerSec
+	if b.tokens > s.burst {
+		b.tokens = s.burst
+	}
+	b.lastRefill = now
+
+	if b.tokens < 1 {
+		return false
+	}
+
+	b.tokens--
+	return true
+}
+
+// tailSampler always keeps observations that exceed threshold latency or
+// carry a non-nil error, and drops everything else.
+type tailSampler struct {
+	threshold time.Duration
+}
+
+// NewTailSampler returns a Sampler that keeps an observation whenever
+// elapsed exceeds threshold or err is non-nil.
+func NewTailSampler(threshold time.Duration) Sampler {
+	return &tailSampler{threshold: threshold}
+}
+
+func (s *tailSampler) Sample(_ string, elapsed time.Duration, err error) bool {
+	return err != nil || elapsed > s.threshold
+}
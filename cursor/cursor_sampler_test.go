@@ -0,0 +1,86 @@
+package rkcursor
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestFixedRateSamplerKeepsOneInN(t *testing.T) {
+	s := NewFixedRateSampler(3)
+
+	var kept int
+	for i := 0; i < 9; i++ {
+		if s.Sample("op", 0, nil) {
+			kept++
+		}
+	}
+
+	if kept != 3 {
+		t.Fatalf("expected 3 kept samples out of 9, got %d", kept)
+	}
+}
+
+func TestFixedRateSamplerCountsPerOperation(t *testing.T) {
+	s := NewFixedRateSampler(2)
+
+	if s.Sample("a", 0, nil) {
+		t.Fatal("expected 1st call for operation a to be dropped")
+	}
+	if !s.Sample("a", 0, nil) {
+		t.Fatal("expected 2nd call for operation a to be kept")
+	}
+
+	// operation b's counter must be independent of a's.
+	if s.Sample("b", 0, nil) {
+		t.Fatal("expected 1st call for operation b to be dropped, not inherit a's count")
+	}
+	if !s.Sample("b", 0, nil) {
+		t.Fatal("expected 2nd call for operation b to be kept")
+	}
+}
+
+func TestTokenBucketSamplerAllowsBurstThenDropsWhenExhausted(t *testing.T) {
+	s := NewTokenBucketSampler(0, 2) // no refill, burst of 2
+
+	if !s.Sample("op", 0, nil) {
+		t.Fatal("expected 1st sample to be kept (burst)")
+	}
+	if !s.Sample("op", 0, nil) {
+		t.Fatal("expected 2nd sample to be kept (burst)")
+	}
+	if s.Sample("op", 0, nil) {
+		t.Fatal("expected 3rd sample to be dropped once the burst is exhausted")
+	}
+}
+
+func TestTokenBucketSamplerRefillsOverTime(t *testing.T) {
+	s := NewTokenBucketSampler(1000, 1) // fast refill, burst of 1
+
+	if !s.Sample("op", 0, nil) {
+		t.Fatal("expected 1st sample to be kept")
+	}
+	if s.Sample("op", 0, nil) {
+		t.Fatal("expected 2nd sample to be dropped immediately after exhausting the burst")
+	}
+
+	time.Sleep(5 * time.Millisecond)
+
+	if !s.Sample("op", 0, nil) {
+		t.Fatal("expected a sample to be kept again after the bucket refills")
+	}
+}
+
+func TestTailSamplerKeepsSlowOrErroringCalls(t *testing.T) {
+	s := NewTailSampler(100 * time.Millisecond)
+
+	if s.Sample("op", 50*time.Millisecond, nil) {
+		t.Fatal("expected a fast, error-free call to be dropped")
+	}
+	if !s.Sample("op", 200*time.Millisecond, nil) {
+		t.Fatal("expected a call above the threshold to be sampled")
+	}
+	if !s.Sample("op", 10*time.Millisecond, errors.New("boom")) {
+		t.Fatal("expected an erroring call to be sampled regardless of latency")
+	}
+}
@@ -0,0 +1,76 @@
+package rkcursor
+
+import (
+	"context"
+	"sync"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// globalTracer is used by cursors that don't specify their own tracer via
+// CustomCursor.Tracer. Nil disables tracing. Guarded by tracerMutex since
+// SetTracerProvider can race with startSpan on the cursor hot path.
+var (
+	tracerMutex  sync.Mutex
+	globalTracer trace.Tracer
+)
+
+// SetTracerProvider configures the OTel TracerProvider global cursors pull
+// spans from. Pass nil to disable tracing.
+func SetTracerProvider(tp trace.TracerProvider) {
+	tracerMutex.Lock()
+	defer tracerMutex.Unlock()
+
+	if tp == nil {
+		globalTracer = nil
+		return
+	}
+
+	globalTracer = tp.Tracer("rk-entry/cursor")
+}
+
+// startSpan opens a span named after operation if tracer (or, if nil, the
+// global tracer) is configured. It returns ctx unchanged and a nil span if
+// tracing is disabled, so callers can treat the result uniformly.
+func startSpan(ctx context.Context, tracer trace.Tracer, operation, entryName, entryType string) (context.Context, trace.Span) {
+	if tracer == nil {
+		tracerMutex.Lock()
+		tracer = globalTracer
+		tracerMutex.Unlock()
+	}
+	if tracer == nil {
+		return ctx, nil
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	ctx, span := tracer.Start(ctx, operation)
+	span.SetAttributes(
+		attribute.String("operation", operation),
+		attribute.String("entryName", entryName),
+		attribute.String("entryType", entryType),
+	)
+
+	return ctx, span
+}
+
+// endSpan records the final status attribute on span and closes it. No-op
+// if span is nil.
+func endSpan(span trace.Span, err error) {
+	if span == nil {
+		return
+	}
+
+	status := "OK"
+	if err != nil {
+		status = "ERROR"
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	span.SetAttributes(attribute.String("status", status))
+	span.End()
+}